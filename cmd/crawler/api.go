@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/node-crawler/pkg/crawler"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -15,11 +18,49 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/node-crawler/pkg/api"
 	"github.com/ethereum/node-crawler/pkg/apidb"
+	"github.com/ethereum/node-crawler/pkg/backoff"
 	"github.com/ethereum/node-crawler/pkg/crawlerdb"
+	"github.com/ethereum/node-crawler/pkg/discovery"
+	"github.com/ethereum/node-crawler/pkg/geoip"
+	"github.com/ethereum/node-crawler/pkg/leaderlock"
+	"github.com/ethereum/node-crawler/pkg/readiness"
+	"github.com/ethereum/node-crawler/pkg/warc"
 	"github.com/urfave/cli/v2"
 )
 
+// warcRotateSize is the number of uncompressed bytes written to a WARC
+// output file after which it is closed and a new one started.
+const warcRotateSize = 512 * 1024 * 1024
+
+// shutdownDrainTimeout bounds how long startAPI waits for in-flight work to
+// finish after a shutdown signal, so a stuck SQLite transaction can't hang
+// the process forever.
+const shutdownDrainTimeout = 30 * time.Second
+
+// Backoff/failure-budget settings shared by the daemons in this file.
+const (
+	daemonBackoffBase            = time.Second
+	daemonBackoffCap             = 15 * time.Minute
+	daemonMaxConsecutiveFailures = 10
+)
+
 var (
+	warcOutputFlag = &cli.StringFlag{
+		Name:  "warc-output",
+		Usage: "Directory to write a rotating, gzip-compressed WARC archive of crawled node records to. Disabled if empty.",
+	}
+
+	discoveryFlag = &cli.StringSliceFlag{
+		Name:  "discovery",
+		Usage: "Discovery backends to run concurrently, tagging found nodes with whichever one found them (v4, v5, static)",
+		Value: cli.NewStringSlice(discovery.BackendV4),
+	}
+
+	bootlistFlag = &cli.StringFlag{
+		Name:  "discovery.bootlist",
+		Usage: "ENR file to read nodes from for the static discovery backend",
+	}
+
 	apiCommand = &cli.Command{
 		Name:   "api",
 		Usage:  "API server for the crawler",
@@ -29,7 +70,9 @@ var (
 			apiListenAddrFlag,
 			autovacuumFlag,
 			busyTimeoutFlag,
+			bootlistFlag,
 			crawlerDBFlag,
+			discoveryFlag,
 			dropNodesTimeFlag,
 			listenAddrFlag,
 			bootnodesFlag,
@@ -39,6 +82,7 @@ var (
 			nodedbFlag,
 			nodekeyFlag,
 			timeoutFlag,
+			warcOutputFlag,
 			workersFlag,
 			utils.GoerliFlag,
 			utils.NetworkIdFlag,
@@ -48,14 +92,39 @@ var (
 )
 
 func startAPI(ctx *cli.Context) error {
+	rootCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	var (
 		crawlerDBPath        = ctx.String(crawlerDBFlag.Name)
 		apiDBPath            = ctx.String(apiDBFlag.Name)
 		autovacuum           = ctx.String(autovacuumFlag.Name)
 		busyTimeout          = ctx.Uint64(busyTimeoutFlag.Name)
 		crawlerListeningAddr = ctx.String(listenAddrFlag.Name)
+		warcOutputDir        = ctx.String(warcOutputFlag.Name)
+		geoipDBPath          = ctx.String(geoipdbFlag.Name)
 	)
 
+	var warcWriter *warc.Writer
+	if warcOutputDir != "" {
+		w, err := warc.NewWriter(warcOutputDir, "node-crawler", warcRotateSize)
+		if err != nil {
+			return fmt.Errorf("opening warc output: %w", err)
+		}
+		defer w.Close()
+		warcWriter = w
+	}
+
+	var geoipDB *geoip.DB
+	if geoipDBPath != "" {
+		db, err := geoip.Open(geoipDBPath)
+		if err != nil {
+			return fmt.Errorf("opening geoip db: %w", err)
+		}
+		defer db.Close()
+		geoipDB = db
+	}
+
 	crawlerDB, err := openSQLiteDB(
 		crawlerDBPath,
 		autovacuum,
@@ -83,41 +152,67 @@ func startAPI(ctx *cli.Context) error {
 			return err
 		}
 	}
+	if err := leaderlock.CreateTable(nodeDB); err != nil {
+		return err
+	}
 
 	enodeDB, err := enode.OpenDB(ctx.String(nodedbFlag.Name))
 	if err != nil {
 		panic(err)
 	}
 
+	discoveryBackends, err := validateDiscoveryBackends(ctx.StringSlice(discoveryFlag.Name))
+	if err != nil {
+		return err
+	}
+	log.Info("Discovery backends enabled", "backends", discoveryBackends)
+
 	crawler := crawler.Crawler{
-		NetworkID:  ctx.Uint64(utils.NetworkIdFlag.Name),
-		NodeURL:    ctx.String(nodeURLFlag.Name),
-		ListenAddr: crawlerListeningAddr,
-		NodeKey:    ctx.String(nodekeyFlag.Name),
-		Bootnodes:  ctx.StringSlice(bootnodesFlag.Name),
-		Timeout:    ctx.Duration(timeoutFlag.Name),
-		Workers:    ctx.Uint64(workersFlag.Name),
-		Sepolia:    ctx.Bool(utils.SepoliaFlag.Name),
-		Goerli:     ctx.Bool(utils.GoerliFlag.Name),
-		NodeDB:     enodeDB,
-		CrawlerDB:  crawlerDB,
+		NetworkID:         ctx.Uint64(utils.NetworkIdFlag.Name),
+		NodeURL:           ctx.String(nodeURLFlag.Name),
+		ListenAddr:        crawlerListeningAddr,
+		NodeKey:           ctx.String(nodekeyFlag.Name),
+		Bootnodes:         ctx.StringSlice(bootnodesFlag.Name),
+		Timeout:           ctx.Duration(timeoutFlag.Name),
+		Workers:           ctx.Uint64(workersFlag.Name),
+		Sepolia:           ctx.Bool(utils.SepoliaFlag.Name),
+		Goerli:            ctx.Bool(utils.GoerliFlag.Name),
+		NodeDB:            enodeDB,
+		CrawlerDB:         crawlerDB,
+		DiscoveryBackends: discoveryBackends,
+		BootlistFile:      ctx.String(bootlistFlag.Name),
+		GeoIP:             geoipDB,
 	}
 	log.Info(fmt.Sprintf("1. crawler listen address: %v : %v", crawler.ListenAddr, ctx.String(listenAddrFlag.Name)))
 
+	// readyGate closes once the first transferNewNodes cycle has succeeded
+	// and the crawler has completed one full discovery round, for /readyz to
+	// key off of. A standby instance that never becomes leader signals its
+	// half in standbyReadiness below instead of from newNodeDaemon.
+	readyGate := readiness.NewGate(2)
+
 	// Start daemons
 	var wg sync.WaitGroup
 	wg.Add(3)
 
-	// Start reading daemon
+	// The reading and drop daemons write to the shared SQLite files, so only
+	// the instance holding the leader_lock lease may run them. Every
+	// instance, leader or not, keeps serving read-only API traffic.
+	dropNodesTime := ctx.Duration(dropNodesTimeFlag.Name)
+	lock := leaderlock.New(nodeDB, "api-daemons")
 	go func() {
 		defer wg.Done()
-		newNodeDaemon(crawlerDB, nodeDB)
+		runLeaderDaemons(rootCtx, lock, crawlerDB, nodeDB, warcWriter, readyGate, dropNodesTime)
 	}()
 
-	// Start the drop daemon
+	// A standby instance never runs newNodeDaemon, so it would otherwise
+	// never signal readyGate and would report /readyz 503 forever even
+	// though it is correctly serving read-only API traffic. If this instance
+	// is not the leader once a full lease period has passed, treat that half
+	// of the gate as satisfied.
 	go func() {
 		defer wg.Done()
-		dropDaemon(nodeDB, ctx.Duration(dropNodesTimeFlag.Name))
+		standbyReadiness(rootCtx, lock, readyGate)
 	}()
 
 	// Start the API deamon
@@ -126,14 +221,119 @@ func startAPI(ctx *cli.Context) error {
 	go func() {
 		defer wg.Done()
 		log.Info(fmt.Sprintf("2. crawler listen address: %v", &apiDaemon.Crawler.ListenAddr))
-		apiDaemon.HandleRequests()
+		if err := apiDaemon.HandleRequests(rootCtx, readyGate); err != nil {
+			log.Error("API daemon stopped", "err", err)
+		}
 	}()
-	wg.Wait()
+
+	<-rootCtx.Done()
+	log.Info("Shutdown signal received, draining in-flight work", "timeout", shutdownDrainTimeout)
+	if !waitWithTimeout(&wg, shutdownDrainTimeout) {
+		log.Warn("Timed out draining daemons before shutdown", "timeout", shutdownDrainTimeout)
+	}
 
 	return nil
 }
 
-func transferNewNodes(crawlerDB, nodeDB *sql.DB) error {
+// waitWithTimeout waits for wg, returning false if timeout elapses first.
+func waitWithTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// runLeaderDaemons blocks running the leader-election loop, starting the
+// reading and drop daemons whenever this instance holds the lease and
+// stopping them as soon as it is lost.
+func runLeaderDaemons(
+	ctx context.Context,
+	lock *leaderlock.Lock,
+	crawlerDB, nodeDB *sql.DB,
+	warcWriter *warc.Writer,
+	readyGate *readiness.Gate,
+	dropNodesTime time.Duration,
+) {
+	var (
+		daemonsWG     sync.WaitGroup
+		cancelDaemons context.CancelFunc
+		transferReady sync.Once
+	)
+
+	// The guards and the readiness signal outlive any single leadership
+	// term: each guard registers Prometheus series that may only be
+	// registered once per name, and the transfer half of readyGate is
+	// signalled at most once.
+	newNodeGuard := backoff.NewGuard("new_node", daemonBackoffBase, daemonBackoffCap, daemonMaxConsecutiveFailures)
+	dropGuard := backoff.NewGuard("drop_nodes", daemonBackoffBase, daemonBackoffCap, daemonMaxConsecutiveFailures)
+	signalTransferReady := func() { transferReady.Do(readyGate.Signal) }
+
+	lock.Run(
+		ctx,
+		func() {
+			var daemonsCtx context.Context
+			daemonsCtx, cancelDaemons = context.WithCancel(ctx)
+
+			daemonsWG.Add(2)
+			go func() {
+				defer daemonsWG.Done()
+				newNodeDaemon(daemonsCtx, crawlerDB, nodeDB, warcWriter, newNodeGuard, signalTransferReady)
+			}()
+			go func() {
+				defer daemonsWG.Done()
+				dropDaemon(daemonsCtx, nodeDB, dropNodesTime, dropGuard)
+			}()
+		},
+		func() {
+			cancelDaemons()
+			daemonsWG.Wait()
+		},
+	)
+}
+
+// standbyReadiness signals readyGate's transfer-cycle precondition on behalf
+// of an instance that never becomes leader, so a hot-standby keeps reporting
+// /readyz ready even though it never runs newNodeDaemon. It waits one lease
+// period and then signals only if this instance is not the leader at that
+// moment, since a leader's newNodeDaemon signals for itself; it does nothing
+// if ctx is cancelled first.
+func standbyReadiness(ctx context.Context, lock *leaderlock.Lock, readyGate *readiness.Gate) {
+	timer := time.NewTimer(lock.Lease)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+		if !lock.IsLeader() {
+			readyGate.Signal()
+		}
+	}
+}
+
+// validateDiscoveryBackends checks that every requested backend name is
+// recognized, returning them unchanged so the caller can pass them straight
+// through to discovery.New for each one it wants to run concurrently.
+func validateDiscoveryBackends(backends []string) ([]string, error) {
+	for _, b := range backends {
+		switch b {
+		case discovery.BackendV4, discovery.BackendV5, discovery.BackendStatic:
+		default:
+			return nil, fmt.Errorf("unknown discovery backend: %q", b)
+		}
+	}
+
+	return backends, nil
+}
+
+func transferNewNodes(crawlerDB, nodeDB *sql.DB, warcWriter *warc.Writer) error {
 	crawlerDBTx, err := crawlerDB.Begin()
 	if err != nil {
 		// Sometimes error occur trying to read the crawler database, but
@@ -158,41 +358,101 @@ func transferNewNodes(crawlerDB, nodeDB *sql.DB) error {
 			return fmt.Errorf("error inserting nodes: %w", err)
 		}
 		log.Info("Nodes inserted", "len", len(nodes))
+
+		if warcWriter != nil {
+			archiveNodes(warcWriter, nodes)
+		}
 	}
 
 	crawlerDBTx.Commit()
 	return nil
 }
 
+// archiveNodes writes each node to the WARC archive. Archival is best-effort:
+// a write failure is logged and otherwise ignored so that a broken archive
+// sink never stalls node insertion.
+func archiveNodes(warcWriter *warc.Writer, nodes []crawlerdb.NodeJSON) {
+	for _, node := range nodes {
+		err := warcWriter.WriteNode(warc.NodeRecord{
+			EnodeURL:     node.URL,
+			ClientName:   node.Name,
+			ClientVer:    node.Version,
+			Capabilities: node.Capabilities,
+			ForkID:       node.ForkID,
+			Timestamp:    time.Now(),
+			GeoIPCountry: node.Country,
+			GeoIPCity:    node.City,
+		})
+		if err != nil {
+			log.Error("Failure writing node to warc archive", "enode", node.URL, "err", err)
+		}
+	}
+}
+
 // newNodeDaemon reads new nodes from the crawler and puts them in the db
 // Might trigger the invalidation of caches for the api in the future
-func newNodeDaemon(crawlerDB, nodeDB *sql.DB) {
-	// Exponentially increase the backoff time
-	retryTimeout := time.Minute
-
+// signalReady is called after every successful cycle.
+func newNodeDaemon(ctx context.Context, crawlerDB, nodeDB *sql.DB, warcWriter *warc.Writer, guard *backoff.Guard, signalReady func()) {
 	for {
-		err := transferNewNodes(crawlerDB, nodeDB)
+		err := transferNewNodes(crawlerDB, nodeDB, warcWriter)
 		if err != nil {
 			log.Error("Failure in transferring new nodes", "err", err)
-			time.Sleep(retryTimeout)
-			retryTimeout *= 2
+
+			sleep := guard.RecordFailure(err)
+			if guard.Tripped() {
+				log.Error("new_node daemon hit a schema error, stopping", "err", err)
+				return
+			}
+			if sleep > 0 && !sleepOrDone(ctx, sleep) {
+				return
+			}
 			continue
 		}
 
-		retryTimeout = time.Minute
-		time.Sleep(time.Second)
+		guard.RecordSuccess()
+		signalReady()
+
+		if !sleepOrDone(ctx, time.Second) {
+			return
+		}
 	}
 }
 
-func dropDaemon(db *sql.DB, dropTimeout time.Duration) {
+func dropDaemon(ctx context.Context, db *sql.DB, dropTimeout time.Duration, guard *backoff.Guard) {
 	ticker := time.NewTicker(10 * time.Minute)
 	defer ticker.Stop()
 
 	for {
-		<-ticker.C
-		err := apidb.DropOldNodes(db, dropTimeout)
-		if err != nil {
-			panic(err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := apidb.DropOldNodes(db, dropTimeout)
+			if err != nil {
+				log.Error("Failure dropping old nodes", "err", err)
+
+				guard.RecordFailure(err)
+				if guard.Tripped() {
+					log.Error("drop_nodes daemon hit a schema error, stopping", "err", err)
+					return
+				}
+				continue
+			}
+
+			guard.RecordSuccess()
 		}
 	}
 }
+
+// sleepOrDone sleeps for d, returning false early if ctx is cancelled first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
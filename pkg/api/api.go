@@ -0,0 +1,79 @@
+// Package api serves the read-only HTTP API backed by the api DB, and
+// drives the crawl that keeps it fed.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/node-crawler/pkg/crawler"
+	"github.com/ethereum/node-crawler/pkg/readiness"
+)
+
+// shutdownTimeout bounds how long the HTTP server gets to finish in-flight
+// requests once its context is cancelled.
+const shutdownTimeout = 10 * time.Second
+
+// Daemon serves the node-crawler HTTP API and owns the Crawler that feeds
+// it.
+type Daemon struct {
+	Crawler crawler.Crawler
+
+	db   *sql.DB
+	addr string
+}
+
+// New returns a Daemon that will serve addr, reading from db, once
+// HandleRequests is called.
+func New(addr string, db *sql.DB, crawler crawler.Crawler) *Daemon {
+	return &Daemon{
+		Crawler: crawler,
+		db:      db,
+		addr:    addr,
+	}
+}
+
+// HandleRequests starts the Daemon's Crawler in the background and serves
+// the HTTP API, including /healthz and /readyz, until ctx is cancelled. It
+// signals readyGate once the crawler has completed one full discovery
+// round; callers are responsible for signalling any other preconditions
+// (e.g. the api daemon's own transfer cycle) on the same gate.
+func (d *Daemon) HandleRequests(ctx context.Context, readyGate *readiness.Gate) error {
+	go func() {
+		if err := d.Crawler.Run(ctx, readyGate.Signal); err != nil {
+			log.Error("Crawler stopped", "err", err)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", HealthzHandler())
+	mux.HandleFunc("/readyz", ReadyzHandler(readyGate))
+	d.registerRoutes(mux)
+
+	server := &http.Server{
+		Addr:    d.addr,
+		Handler: mux,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		return server.Shutdown(shutdownCtx)
+
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ethereum/node-crawler/pkg/readiness"
+)
+
+// ReadyzHandler returns 200 once gate is ready and 503 otherwise, so a load
+// balancer can hold off sending traffic to an instance that hasn't
+// completed its first transfer/discovery cycle yet.
+func ReadyzHandler(gate *readiness.Gate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !gate.Ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}
+}
+
+// HealthzHandler reports liveness: it returns 200 as long as the process is
+// up and able to serve HTTP requests at all.
+func HealthzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}
+}
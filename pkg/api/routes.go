@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// registerRoutes mounts the read-only node-crawler API on mux.
+func (d *Daemon) registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/v1/nodes", d.handleListNodes)
+}
+
+type nodeSummary struct {
+	URL       string `json:"url"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Backend   string `json:"backend"`
+	FirstSeen string `json:"firstSeen"`
+	LastSeen  string `json:"lastSeen"`
+}
+
+func (d *Daemon) handleListNodes(w http.ResponseWriter, r *http.Request) {
+	rows, err := d.db.Query(`
+		SELECT url, name, version, backend, first_seen, last_seen FROM nodes
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var nodes []nodeSummary
+	for rows.Next() {
+		var n nodeSummary
+		if err := rows.Scan(&n.URL, &n.Name, &n.Version, &n.Backend, &n.FirstSeen, &n.LastSeen); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(nodes)
+}
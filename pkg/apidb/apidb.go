@@ -0,0 +1,93 @@
+// Package apidb is the schema and access helpers for the api-facing SQLite
+// database: the durable store of crawled node records that pkg/api serves
+// read-only queries from.
+package apidb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/node-crawler/pkg/crawlerdb"
+)
+
+// CreateDB creates every table the api DB needs if they don't already
+// exist. It is only called once, the first time the DB file is created.
+func CreateDB(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS nodes (
+			url          TEXT PRIMARY KEY,
+			name         TEXT NOT NULL DEFAULT '',
+			version      TEXT NOT NULL DEFAULT '',
+			capabilities TEXT NOT NULL DEFAULT '',
+			fork_id      TEXT NOT NULL DEFAULT '',
+			country      TEXT NOT NULL DEFAULT '',
+			city         TEXT NOT NULL DEFAULT '',
+			backend      TEXT NOT NULL DEFAULT '',
+			first_seen   DATETIME NOT NULL,
+			last_seen    DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("creating nodes table: %w", err)
+	}
+
+	return nil
+}
+
+// InsertCrawledNodes upserts every node crawlerdb handed back from the
+// crawler DB into the api DB, refreshing last_seen and the backend that
+// found it.
+func InsertCrawledNodes(db *sql.DB, nodes []crawlerdb.NodeJSON) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction to insert nodes: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, n := range nodes {
+		_, err := tx.Exec(`
+			INSERT INTO nodes (url, name, version, capabilities, fork_id, country, city, backend, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(url) DO UPDATE SET
+				name         = excluded.name,
+				version      = excluded.version,
+				capabilities = excluded.capabilities,
+				fork_id      = excluded.fork_id,
+				country      = excluded.country,
+				city         = excluded.city,
+				backend      = excluded.backend,
+				last_seen    = excluded.last_seen
+		`,
+			n.URL,
+			n.Name,
+			n.Version,
+			strings.Join(n.Capabilities, ","),
+			n.ForkID,
+			n.Country,
+			n.City,
+			n.Backend,
+			now,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("inserting node %s: %w", n.URL, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DropOldNodes deletes every node whose last_seen is older than dropTimeout.
+func DropOldNodes(db *sql.DB, dropTimeout time.Duration) error {
+	cutoff := time.Now().UTC().Add(-dropTimeout)
+
+	_, err := db.Exec(`DELETE FROM nodes WHERE last_seen < ?`, cutoff)
+	if err != nil {
+		return fmt.Errorf("dropping old nodes: %w", err)
+	}
+
+	return nil
+}
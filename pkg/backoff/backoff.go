@@ -0,0 +1,47 @@
+// Package backoff implements decorrelated-jitter exponential backoff for
+// the daemons in cmd/crawler, along with a bounded consecutive-failure
+// budget and error classification so that lock contention, which is
+// expected and recoverable, doesn't burn through the same budget as a
+// genuine schema mismatch.
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff computes decorrelated-jitter sleep durations: each one is a
+// random value between base and three times the previous sleep, capped at
+// max. See https://www.awsarchitectureblog.com/2015/03/backoff.html.
+type Backoff struct {
+	base time.Duration
+	cap  time.Duration
+	prev time.Duration
+}
+
+// New returns a Backoff that never sleeps less than base or more than cap.
+func New(base, cap time.Duration) *Backoff {
+	return &Backoff{base: base, cap: cap, prev: base}
+}
+
+// Next returns the next sleep duration and advances the sequence.
+func (b *Backoff) Next() time.Duration {
+	upper := b.prev * 3
+	if upper < b.base {
+		upper = b.base
+	}
+
+	d := b.base + time.Duration(rand.Int63n(int64(upper-b.base)+1))
+	if d > b.cap {
+		d = b.cap
+	}
+
+	b.prev = d
+	return d
+}
+
+// Reset returns the sequence to its starting point, as if no failures had
+// been recorded yet.
+func (b *Backoff) Reset() {
+	b.prev = b.base
+}
@@ -0,0 +1,41 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffNextWithinBounds(t *testing.T) {
+	base := 10 * time.Millisecond
+	cap := 100 * time.Millisecond
+	b := New(base, cap)
+
+	prev := base
+	for i := 0; i < 50; i++ {
+		d := b.Next()
+		if d < base {
+			t.Fatalf("Next() = %v, want >= base %v", d, base)
+		}
+		if d > cap {
+			t.Fatalf("Next() = %v, want <= cap %v", d, cap)
+		}
+		if d > prev*3 && d != cap {
+			t.Fatalf("Next() = %v, want <= 3x previous sleep %v (unless capped)", d, prev*3)
+		}
+		prev = d
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	base := 10 * time.Millisecond
+	b := New(base, time.Second)
+
+	for i := 0; i < 10; i++ {
+		b.Next()
+	}
+
+	b.Reset()
+	if b.prev != base {
+		t.Fatalf("after Reset, prev = %v, want base %v", b.prev, base)
+	}
+}
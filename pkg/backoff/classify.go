@@ -0,0 +1,55 @@
+package backoff
+
+import "strings"
+
+// Class categorizes a daemon failure so the caller can decide whether it
+// should count against the consecutive-failure budget.
+type Class int
+
+const (
+	// Transient errors are assumed to be recoverable on their own; they
+	// count against the consecutive-failure budget.
+	Transient Class = iota
+	// Contention errors (SQLite busy/locked) are expected under normal
+	// operation and reset the consecutive-failure counter instead of
+	// growing it.
+	Contention
+	// Schema errors indicate the database doesn't look like the daemon
+	// expects (missing table/column) and won't resolve by retrying, so
+	// they trip the circuit breaker immediately.
+	Schema
+)
+
+// sqliteContentionMarkers are substrings of modernc.org/sqlite and
+// mattn/go-sqlite3 error messages for SQLITE_BUSY / SQLITE_LOCKED.
+var sqliteContentionMarkers = []string{
+	"sqlite_busy",
+	"database is locked",
+	"database table is locked",
+}
+
+var sqliteSchemaMarkers = []string{
+	"no such table",
+	"no such column",
+	"sqlite_error: table",
+}
+
+// Classify inspects err's message to decide which bucket it falls into.
+// err == nil is never passed in; callers only classify on failure.
+func Classify(err error) Class {
+	msg := strings.ToLower(err.Error())
+
+	for _, m := range sqliteContentionMarkers {
+		if strings.Contains(msg, m) {
+			return Contention
+		}
+	}
+
+	for _, m := range sqliteSchemaMarkers {
+		if strings.Contains(msg, m) {
+			return Schema
+		}
+	}
+
+	return Transient
+}
@@ -0,0 +1,25 @@
+package backoff
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Class
+	}{
+		{errors.New("database is locked"), Contention},
+		{errors.New("SQLITE_BUSY: database table is locked"), Contention},
+		{errors.New("no such table: crawled_nodes"), Schema},
+		{errors.New("no such column: url"), Schema},
+		{errors.New("connection reset by peer"), Transient},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package backoff
+
+import "time"
+
+// Guard wraps a Backoff with a bounded consecutive-failure budget and
+// Prometheus metrics, for daemons that loop forever and need to survive
+// transient failures without sleeping for longer and longer or panicking
+// outright.
+type Guard struct {
+	backoff     *Backoff
+	maxFailures int
+	failures    int
+	tripped     bool
+
+	metrics *Metrics
+}
+
+// NewGuard returns a Guard named name, whose backoff sleeps between base and
+// cap, and which, after maxConsecutiveFailures transient failures in a row,
+// sleeps for cap once and starts its backoff sequence over.
+func NewGuard(name string, base, cap time.Duration, maxConsecutiveFailures int) *Guard {
+	return &Guard{
+		backoff:     New(base, cap),
+		maxFailures: maxConsecutiveFailures,
+		metrics:     NewMetrics(name),
+	}
+}
+
+// Tripped reports whether a schema error has permanently disabled this
+// Guard. Once tripped, the daemon should stop calling RecordFailure/Success
+// and surface the condition instead of continuing to retry.
+func (g *Guard) Tripped() bool {
+	return g.tripped
+}
+
+// RecordSuccess resets the failure counter and backoff sequence.
+func (g *Guard) RecordSuccess() {
+	g.failures = 0
+	g.backoff.Reset()
+	g.metrics.successes.Inc()
+	g.metrics.currentBackoff.Set(0)
+}
+
+// RecordFailure classifies err and returns how long the caller should sleep
+// before retrying. A zero duration means the circuit just tripped and the
+// caller should stop looping instead of sleeping at all.
+func (g *Guard) RecordFailure(err error) time.Duration {
+	g.metrics.retries.Inc()
+
+	switch Classify(err) {
+	case Contention:
+		// Expected under concurrent access; don't let it count toward the
+		// budget, just keep backing off.
+		g.failures = 0
+		return g.sleep()
+
+	case Schema:
+		g.tripped = true
+		g.metrics.circuitTrips.Inc()
+		return 0
+
+	default:
+		g.failures++
+		if g.failures >= g.maxFailures {
+			// The budget is exhausted: sleep a full cap-length interval,
+			// not an immediate retry, then start the sequence over.
+			g.metrics.budgetExhausted.Inc()
+			maxSleep := g.backoff.cap
+			g.failures = 0
+			g.backoff.Reset()
+			g.metrics.currentBackoff.Set(maxSleep.Seconds())
+			return maxSleep
+		}
+		return g.sleep()
+	}
+}
+
+func (g *Guard) sleep() time.Duration {
+	d := g.backoff.Next()
+	g.metrics.currentBackoff.Set(d.Seconds())
+	return d
+}
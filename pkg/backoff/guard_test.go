@@ -0,0 +1,69 @@
+package backoff
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGuardRecordFailureContentionDoesNotCountTowardBudget(t *testing.T) {
+	g := NewGuard("guard_contention", time.Millisecond, time.Second, 2)
+
+	for i := 0; i < 10; i++ {
+		g.RecordFailure(errors.New("database is locked"))
+	}
+
+	if g.Tripped() {
+		t.Fatal("contention errors should never trip the circuit breaker")
+	}
+	if g.failures != 0 {
+		t.Fatalf("failures = %d, want 0 (contention resets the budget)", g.failures)
+	}
+}
+
+func TestGuardRecordFailureSchemaTripsImmediately(t *testing.T) {
+	g := NewGuard("guard_schema", time.Millisecond, time.Second, 5)
+
+	sleep := g.RecordFailure(errors.New("no such table: crawled_nodes"))
+	if !g.Tripped() {
+		t.Fatal("schema error should trip the circuit breaker")
+	}
+	if sleep != 0 {
+		t.Fatalf("sleep = %v, want 0 once tripped", sleep)
+	}
+}
+
+func TestGuardRecordFailureBudgetExhaustionSleepsAtCapInsteadOfRetryingImmediately(t *testing.T) {
+	cap := 50 * time.Millisecond
+	g := NewGuard("guard_budget", time.Millisecond, cap, 3)
+
+	var last time.Duration
+	for i := 0; i < 3; i++ {
+		last = g.RecordFailure(errors.New("connection reset"))
+	}
+
+	if last != cap {
+		t.Fatalf("sleep on budget exhaustion = %v, want backoff cap %v", last, cap)
+	}
+	if g.Tripped() {
+		t.Fatal("budget exhaustion should reset and keep retrying, not trip the breaker")
+	}
+	if g.failures != 0 {
+		t.Fatalf("failures = %d, want 0 after budget exhaustion resets it", g.failures)
+	}
+}
+
+func TestGuardRecordSuccessResetsBackoff(t *testing.T) {
+	g := NewGuard("guard_success", time.Millisecond, time.Second, 3)
+
+	g.RecordFailure(errors.New("connection reset"))
+	g.RecordFailure(errors.New("connection reset"))
+	g.RecordSuccess()
+
+	if g.failures != 0 {
+		t.Fatalf("failures = %d, want 0 after RecordSuccess", g.failures)
+	}
+	if g.backoff.prev != g.backoff.base {
+		t.Fatalf("backoff.prev = %v, want reset to base %v", g.backoff.prev, g.backoff.base)
+	}
+}
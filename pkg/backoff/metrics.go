@@ -0,0 +1,60 @@
+package backoff
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics are the Prometheus series exported for a single Guard, labeled by
+// the daemon name so multiple Guards can share a registry.
+type Metrics struct {
+	retries         prometheus.Counter
+	successes       prometheus.Counter
+	budgetExhausted prometheus.Counter
+	circuitTrips    prometheus.Counter
+	currentBackoff  prometheus.Gauge
+}
+
+// NewMetrics registers and returns the Prometheus series for a daemon named
+// name. It must be called at most once per name per registry.
+func NewMetrics(name string) *Metrics {
+	labels := prometheus.Labels{"daemon": name}
+
+	return &Metrics{
+		retries: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace:   "node_crawler",
+			Subsystem:   "daemon",
+			Name:        "retries_total",
+			Help:        "Number of retried failures, by daemon.",
+			ConstLabels: labels,
+		}),
+		successes: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace:   "node_crawler",
+			Subsystem:   "daemon",
+			Name:        "successes_total",
+			Help:        "Number of successful cycles, by daemon.",
+			ConstLabels: labels,
+		}),
+		budgetExhausted: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace:   "node_crawler",
+			Subsystem:   "daemon",
+			Name:        "failure_budget_exhausted_total",
+			Help:        "Number of times the consecutive-failure budget was exhausted and reset, by daemon.",
+			ConstLabels: labels,
+		}),
+		circuitTrips: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace:   "node_crawler",
+			Subsystem:   "daemon",
+			Name:        "circuit_breaker_trips_total",
+			Help:        "Number of times a schema error tripped the circuit breaker, by daemon.",
+			ConstLabels: labels,
+		}),
+		currentBackoff: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace:   "node_crawler",
+			Subsystem:   "daemon",
+			Name:        "backoff_seconds",
+			Help:        "Current backoff sleep duration in seconds, by daemon.",
+			ConstLabels: labels,
+		}),
+	}
+}
@@ -0,0 +1,288 @@
+// Package crawler drives the devp2p discovery crawl: it runs one or more
+// pluggable discovery.Discoverer backends concurrently, feeds their
+// combined output into the crawler DB tagged with whichever backend found
+// each node, and periodically re-walks the network for fresh nodes.
+package crawler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/node-crawler/pkg/crawlerdb"
+	"github.com/ethereum/node-crawler/pkg/discovery"
+	"github.com/ethereum/node-crawler/pkg/geoip"
+)
+
+// Crawler holds the configuration for a single devp2p crawl and the
+// discovery backends it runs.
+type Crawler struct {
+	NetworkID  uint64
+	NodeURL    string
+	ListenAddr string
+	NodeKey    string
+	Bootnodes  []string
+	Timeout    time.Duration
+	Workers    uint64
+	Sepolia    bool
+	Goerli     bool
+	NodeDB     *enode.DB
+	CrawlerDB  *sql.DB
+
+	// DiscoveryBackends selects which discovery.Discoverer(s) to run
+	// concurrently; defaults to just discovery.BackendV4 if empty.
+	DiscoveryBackends []string
+	// BootlistFile is required when DiscoveryBackends includes
+	// discovery.BackendStatic.
+	BootlistFile string
+
+	// GeoIP, if set, is used to annotate every recorded node with a
+	// country/city looked up from its discovered IP.
+	GeoIP *geoip.DB
+
+	// nodeKey is the identity used for every non-static discovery backend
+	// and for dialing out to peers during the handshake; it is set once by
+	// startBackends.
+	nodeKey *ecdsa.PrivateKey
+}
+
+// Run starts every configured discovery backend and fans their output into
+// the crawler DB tagged with provenance, until ctx is cancelled or every
+// backend's RandomNodes iterator is exhausted. onRoundComplete, if non-nil,
+// is called at most once, the first time roundTimeout elapses after Run
+// starts; it does not wait for any iterator to be exhausted.
+func (c *Crawler) Run(ctx context.Context, onRoundComplete func()) error {
+	if err := crawlerdb.CreateTable(c.CrawlerDB); err != nil {
+		return err
+	}
+
+	backends, err := c.startBackends()
+	if err != nil {
+		return fmt.Errorf("starting discovery backends: %w", err)
+	}
+
+	multi := discovery.NewMulti(backends)
+	defer multi.Close()
+
+	var signalOnce sync.Once
+	roundTimer := time.NewTimer(c.roundTimeout())
+	defer roundTimer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case tagged, ok := <-multi.Nodes():
+			if !ok {
+				return nil
+			}
+			if err := c.recordNode(tagged); err != nil {
+				log.Error("Failed to record discovered node", "enode", tagged.Node.URLv4(), "err", err)
+			}
+
+		case <-roundTimer.C:
+			if onRoundComplete != nil {
+				signalOnce.Do(onRoundComplete)
+			}
+			roundTimer.Reset(c.roundTimeout())
+		}
+	}
+}
+
+// roundTimeout is how long Run waits before considering a discovery round
+// "complete" for readiness purposes, derived from the configured crawl
+// Timeout (or a sane default if unset).
+func (c *Crawler) roundTimeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return time.Minute
+}
+
+// recordNode enriches a freshly discovered node with a real devp2p
+// handshake (client name/version, capabilities, forkID) and a GeoIP lookup
+// before writing it to the crawler DB. Enrichment is best-effort: a peer
+// that doesn't answer still gets recorded with whatever discovery alone
+// told us, since that's still evidence the node exists.
+func (c *Crawler) recordNode(tagged discovery.TaggedNode) error {
+	n := tagged.Node
+
+	node := crawlerdb.NodeJSON{
+		URL:     n.URLv4(),
+		Backend: tagged.Backend,
+		SeenAt:  time.Now().UTC(),
+	}
+
+	if hs, err := c.handshake(c.nodeKey, n); err != nil {
+		log.Debug("Handshake failed", "enode", n.URLv4(), "err", err)
+	} else {
+		node.Name = hs.Name
+		node.Version = hs.Version
+		node.Capabilities = hs.Capabilities
+		node.ForkID = hs.ForkID
+	}
+
+	if c.GeoIP != nil {
+		if country, city, err := c.GeoIP.Lookup(n.IP()); err != nil {
+			log.Debug("GeoIP lookup failed", "enode", n.URLv4(), "err", err)
+		} else {
+			node.Country = country
+			node.City = city
+		}
+	}
+
+	return crawlerdb.InsertFoundNode(c.CrawlerDB, node)
+}
+
+// startBackends constructs one discovery.Discoverer per name in
+// c.DiscoveryBackends (defaulting to discv4 alone), keyed by backend name.
+// All non-static backends share a single UDP socket and enode.LocalNode,
+// the same way geth's own p2p.Server runs discv4 and discv5 side by side:
+// one real net.PacketConn is opened, and if both v4 and v5 are requested,
+// v5 is handed a sharedUDPConn that receives whatever v4 couldn't parse.
+func (c *Crawler) startBackends() (map[string]discovery.Discoverer, error) {
+	names := c.DiscoveryBackends
+	if len(names) == 0 {
+		names = []string{discovery.BackendV4}
+	}
+
+	key, err := c.loadNodeKey()
+	if err != nil {
+		return nil, err
+	}
+	c.nodeKey = key
+
+	var bootN []*enode.Node
+	for _, url := range c.Bootnodes {
+		n, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return nil, fmt.Errorf("parsing bootnode %q: %w", url, err)
+		}
+		bootN = append(bootN, n)
+	}
+
+	hasV4, hasV5 := false, false
+	for _, name := range names {
+		switch name {
+		case discovery.BackendV4:
+			hasV4 = true
+		case discovery.BackendV5:
+			hasV5 = true
+		}
+	}
+
+	var (
+		conn      discover.UDPConn
+		ln        *enode.LocalNode
+		unhandled chan discover.ReadPacket
+	)
+	if hasV4 || hasV5 {
+		conn, ln, err = c.listen(key)
+		if err != nil {
+			return nil, fmt.Errorf("starting udp listener: %w", err)
+		}
+		if hasV4 && hasV5 {
+			unhandled = make(chan discover.ReadPacket, 100)
+		}
+	}
+
+	backends := make(map[string]discovery.Discoverer, len(names))
+	for _, name := range names {
+		if name == discovery.BackendStatic {
+			d, err := discovery.New(name, nil, nil, discovery.Config{BootlistFile: c.BootlistFile})
+			if err != nil {
+				return nil, err
+			}
+			backends[name] = d
+			continue
+		}
+
+		backendConn := conn
+		cfg := discovery.Config{
+			PrivateKey: key,
+			Bootnodes:  bootN,
+		}
+		switch {
+		case name == discovery.BackendV4 && unhandled != nil:
+			// v4 gets the raw conn and forwards whatever it can't parse to
+			// v5 via Unhandled.
+			cfg.Unhandled = unhandled
+		case name == discovery.BackendV5 && unhandled != nil:
+			backendConn = &sharedUDPConn{conn.(*net.UDPConn), unhandled}
+		}
+
+		d, err := discovery.New(name, backendConn, ln, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("starting %s backend: %w", name, err)
+		}
+		backends[name] = d
+	}
+
+	return backends, nil
+}
+
+func (c *Crawler) loadNodeKey() (*ecdsa.PrivateKey, error) {
+	if c.NodeKey != "" {
+		key, err := crypto.HexToECDSA(c.NodeKey)
+		if err != nil {
+			return nil, fmt.Errorf("parsing node key: %w", err)
+		}
+		return key, nil
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generating node key: %w", err)
+	}
+	return key, nil
+}
+
+func (c *Crawler) listen(key *ecdsa.PrivateKey) (discover.UDPConn, *enode.LocalNode, error) {
+	addr, err := net.ResolveUDPAddr("udp", c.ListenAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ln := enode.NewLocalNode(c.NodeDB, key)
+
+	return conn, ln, nil
+}
+
+// sharedUDPConn lets discv5 read whatever packets discv4 couldn't parse off
+// the same socket, mirroring p2p.Server's own sharedUDPConn: Write goes
+// straight to the real connection, while ReadFromUDP pulls from the
+// Unhandled channel discv4 was configured with.
+type sharedUDPConn struct {
+	*net.UDPConn
+	unhandled chan discover.ReadPacket
+}
+
+func (s *sharedUDPConn) ReadFromUDP(b []byte) (n int, addr *net.UDPAddr, err error) {
+	packet, ok := <-s.unhandled
+	if !ok {
+		return 0, nil, errors.New("connection was closed")
+	}
+	n = copy(b, packet.Data)
+	return n, packet.Addr, nil
+}
+
+// Close is a no-op: the underlying *net.UDPConn is owned and closed by
+// discv4, which received it directly.
+func (s *sharedUDPConn) Close() error {
+	return nil
+}
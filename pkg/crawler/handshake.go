@@ -0,0 +1,213 @@
+package crawler
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	ourClientName    = "node-crawler"
+
+	// ethProtocolOffset is the base devp2p protocol length: message codes
+	// 0-15 are reserved for Hello/Disconnect/Ping/Pong, so the first
+	// negotiated sub-protocol's codes start right after. The crawler never
+	// advertises more than one sub-protocol family, so this offset is
+	// always correct without needing real capability negotiation.
+	ethProtocolOffset = 16
+)
+
+// protoHandshake mirrors go-ethereum's own (unexported) p2p.protoHandshake
+// wire struct: the devp2p Hello message exchanged before any sub-protocol
+// runs.
+type protoHandshake struct {
+	Version    uint64
+	Name       string
+	Caps       []p2p.Cap
+	ListenPort uint64
+	ID         []byte
+
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// handshakeResult is everything recordNode learns about a peer by actually
+// connecting to it, beyond what discovery alone provides.
+type handshakeResult struct {
+	Name         string
+	Version      string
+	Capabilities []string
+	ForkID       string
+}
+
+// handshake dials n's TCP port, performs the devp2p RLPx transport
+// handshake and Hello exchange, and - if the peer advertises eth - the eth
+// Status exchange, to learn its client identity, capabilities, and fork ID
+// without syncing any chain data.
+func (c *Crawler) handshake(key *ecdsa.PrivateKey, n *enode.Node) (handshakeResult, error) {
+	addr := &net.TCPAddr{IP: n.IP(), Port: n.TCP()}
+	conn, err := net.DialTimeout("tcp", addr.String(), handshakeTimeout)
+	if err != nil {
+		return handshakeResult{}, fmt.Errorf("dialing: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	rlpxConn := rlpx.NewConn(conn, n.Pubkey())
+	if _, err := rlpxConn.Handshake(key); err != nil {
+		return handshakeResult{}, fmt.Errorf("rlpx handshake: %w", err)
+	}
+
+	theirHello, err := c.helloExchange(rlpxConn, key)
+	if err != nil {
+		return handshakeResult{}, err
+	}
+	// We advertise devp2p version 5, so a peer that does too compresses
+	// every message after Hello with snappy.
+	rlpxConn.SetSnappy(theirHello.Version >= 5)
+
+	name, version := splitClientID(theirHello.Name)
+	result := handshakeResult{Name: name, Version: version}
+
+	caps := make([]string, 0, len(theirHello.Caps))
+	var ethVersion uint
+	for _, cp := range theirHello.Caps {
+		caps = append(caps, cp.String())
+		if cp.Name == "eth" && cp.Version > ethVersion {
+			ethVersion = cp.Version
+		}
+	}
+	result.Capabilities = caps
+
+	if ethVersion == 0 {
+		return result, nil
+	}
+
+	forkID, err := c.statusExchange(rlpxConn, ethVersion)
+	if err != nil {
+		return result, fmt.Errorf("eth status exchange: %w", err)
+	}
+	result.ForkID = forkID
+
+	return result, nil
+}
+
+// helloExchange sends our devp2p Hello and reads the peer's.
+func (c *Crawler) helloExchange(conn *rlpx.Conn, key *ecdsa.PrivateKey) (protoHandshake, error) {
+	ours := &protoHandshake{
+		Version: 5,
+		Name:    ourClientName,
+		Caps: []p2p.Cap{
+			{Name: "eth", Version: 66},
+			{Name: "eth", Version: 67},
+			{Name: "eth", Version: 68},
+		},
+		ID: crypto.FromECDSAPub(&key.PublicKey)[1:],
+	}
+	payload, err := rlp.EncodeToBytes(ours)
+	if err != nil {
+		return protoHandshake{}, fmt.Errorf("encoding hello: %w", err)
+	}
+	if _, err := conn.Write(0, payload); err != nil {
+		return protoHandshake{}, fmt.Errorf("writing hello: %w", err)
+	}
+
+	code, data, _, err := conn.Read()
+	if err != nil {
+		return protoHandshake{}, fmt.Errorf("reading hello: %w", err)
+	}
+	if code != 0 {
+		return protoHandshake{}, fmt.Errorf("expected hello (code 0), got code %d", code)
+	}
+
+	var theirs protoHandshake
+	if err := rlp.DecodeBytes(data, &theirs); err != nil {
+		return protoHandshake{}, fmt.Errorf("decoding hello: %w", err)
+	}
+	return theirs, nil
+}
+
+// splitClientID splits a devp2p client ID like
+// "Geth/v1.13.5-stable/linux-amd64/go1.21.0" into its name and version.
+func splitClientID(name string) (client, version string) {
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) == 0 {
+		return name, ""
+	}
+	client = parts[0]
+	if len(parts) > 1 {
+		version = parts[1]
+	}
+	return client, version
+}
+
+// genesisAndForkID returns the genesis hash and EIP-2124 fork ID for
+// whichever network this Crawler is configured for. head and time are both
+// 0: the crawler never syncs a real chain, so it always claims to be at
+// genesis - peers will see us as far behind, which is fine since we never
+// request blocks from them, only their Status.
+func (c *Crawler) genesisAndForkID() (genesisHash common.Hash, id forkid.ID) {
+	var genesis *core.Genesis
+	switch {
+	case c.Sepolia:
+		genesis = core.DefaultSepoliaGenesisBlock()
+	case c.Goerli:
+		genesis = core.DefaultGoerliGenesisBlock()
+	default:
+		genesis = core.DefaultGenesisBlock()
+	}
+
+	block := genesis.ToBlock()
+	return block.Hash(), forkid.NewID(genesis.Config, block, 0, 0)
+}
+
+// statusExchange performs the eth wire protocol's Status handshake over an
+// already-established RLPx session, returning the peer's fork ID as a hex
+// string.
+func (c *Crawler) statusExchange(conn *rlpx.Conn, version uint) (string, error) {
+	genesisHash, id := c.genesisAndForkID()
+
+	ours := &eth.StatusPacket{
+		ProtocolVersion: uint32(version),
+		NetworkID:       c.NetworkID,
+		TD:              big.NewInt(0),
+		Head:            genesisHash,
+		Genesis:         genesisHash,
+		ForkID:          id,
+	}
+	payload, err := rlp.EncodeToBytes(ours)
+	if err != nil {
+		return "", fmt.Errorf("encoding status: %w", err)
+	}
+	if _, err := conn.Write(ethProtocolOffset+eth.StatusMsg, payload); err != nil {
+		return "", fmt.Errorf("writing status: %w", err)
+	}
+
+	code, data, _, err := conn.Read()
+	if err != nil {
+		return "", fmt.Errorf("reading status: %w", err)
+	}
+	if code != ethProtocolOffset+eth.StatusMsg {
+		return "", fmt.Errorf("expected status (code %d), got code %d", ethProtocolOffset+eth.StatusMsg, code)
+	}
+
+	var theirs eth.StatusPacket
+	if err := rlp.DecodeBytes(data, &theirs); err != nil {
+		return "", fmt.Errorf("decoding status: %w", err)
+	}
+
+	return fmt.Sprintf("%x", theirs.ForkID.Hash), nil
+}
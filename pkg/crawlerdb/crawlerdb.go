@@ -0,0 +1,125 @@
+// Package crawlerdb is the schema and access helpers for the crawler-side
+// SQLite database: the write-behind queue of nodes a crawl has seen but the
+// api daemon hasn't yet copied into the api DB (see pkg/apidb).
+package crawlerdb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NodeJSON is a single crawled node record, as read back out of the
+// crawler DB by ReadAndDeleteUnseenNodes.
+type NodeJSON struct {
+	URL          string
+	Name         string
+	Version      string
+	Capabilities []string
+	ForkID       string
+	Country      string
+	City         string
+	// Backend is the discovery backend that found this node (discv4,
+	// discv5, or static), for provenance.
+	Backend string
+	SeenAt  time.Time
+}
+
+// CreateTable creates the crawled_nodes table if it does not already
+// exist. It is safe to call on every startup.
+func CreateTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS crawled_nodes (
+			url          TEXT PRIMARY KEY,
+			name         TEXT NOT NULL DEFAULT '',
+			version      TEXT NOT NULL DEFAULT '',
+			capabilities TEXT NOT NULL DEFAULT '',
+			fork_id      TEXT NOT NULL DEFAULT '',
+			country      TEXT NOT NULL DEFAULT '',
+			city         TEXT NOT NULL DEFAULT '',
+			backend      TEXT NOT NULL DEFAULT '',
+			seen_at      DATETIME NOT NULL,
+			unseen       INTEGER NOT NULL DEFAULT 1
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("creating crawled_nodes table: %w", err)
+	}
+
+	return nil
+}
+
+// InsertFoundNode records (or refreshes) a node found by one of the
+// discovery backends, marking it unseen so the api daemon's
+// transferNewNodes picks it up.
+func InsertFoundNode(db *sql.DB, node NodeJSON) error {
+	_, err := db.Exec(`
+		INSERT INTO crawled_nodes (url, name, version, capabilities, fork_id, country, city, backend, seen_at, unseen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT(url) DO UPDATE SET
+			name         = excluded.name,
+			version      = excluded.version,
+			capabilities = excluded.capabilities,
+			fork_id      = excluded.fork_id,
+			country      = excluded.country,
+			city         = excluded.city,
+			backend      = excluded.backend,
+			seen_at      = excluded.seen_at,
+			unseen       = 1
+	`,
+		node.URL,
+		node.Name,
+		node.Version,
+		strings.Join(node.Capabilities, ","),
+		node.ForkID,
+		node.Country,
+		node.City,
+		node.Backend,
+		node.SeenAt,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting found node: %w", err)
+	}
+
+	return nil
+}
+
+// ReadAndDeleteUnseenNodes returns every node marked unseen within tx and
+// clears the flag, so the api daemon can copy them into the api DB exactly
+// once.
+func ReadAndDeleteUnseenNodes(tx *sql.Tx) ([]NodeJSON, error) {
+	rows, err := tx.Query(`
+		SELECT url, name, version, capabilities, fork_id, country, city, backend, seen_at
+		FROM crawled_nodes
+		WHERE unseen = 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying unseen nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []NodeJSON
+	for rows.Next() {
+		var (
+			n    NodeJSON
+			caps string
+		)
+		if err := rows.Scan(&n.URL, &n.Name, &n.Version, &caps, &n.ForkID, &n.Country, &n.City, &n.Backend, &n.SeenAt); err != nil {
+			return nil, fmt.Errorf("scanning unseen node: %w", err)
+		}
+		if caps != "" {
+			n.Capabilities = strings.Split(caps, ",")
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading unseen nodes: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE crawled_nodes SET unseen = 0 WHERE unseen = 1`); err != nil {
+		return nil, fmt.Errorf("clearing unseen flag: %w", err)
+	}
+
+	return nodes, nil
+}
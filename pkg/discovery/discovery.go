@@ -0,0 +1,70 @@
+// Package discovery makes the node discovery mechanism used by the crawler
+// pluggable, so that a crawl can pull candidate nodes from discv4, discv5,
+// and/or a static list at the same time and feed them into one shared
+// queue, rather than being hard-coded to discv4.
+package discovery
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Backend names accepted by the --discovery flag.
+const (
+	BackendV4     = "v4"
+	BackendV5     = "v5"
+	BackendStatic = "static"
+)
+
+// Discoverer is the common interface satisfied by every discovery backend.
+// It mirrors the subset of *discover.UDPv4 / *discover.UDPv5 that the
+// crawler actually uses, so those two can be used directly without a
+// wrapper. Lookup is deliberately not part of this interface: *discover.UDPv4
+// only exposes LookupPubkey(*ecdsa.PublicKey), which a bare enode.ID target
+// can't supply, so there is no single Lookup signature both backends share.
+type Discoverer interface {
+	// RandomNodes returns an iterator producing random nodes known to
+	// this backend.
+	RandomNodes() enode.Iterator
+	// Close shuts the backend down and releases its resources.
+	Close()
+}
+
+// Config holds everything needed to start any of the discovery backends.
+type Config struct {
+	PrivateKey *ecdsa.PrivateKey
+	Bootnodes  []*enode.Node
+	Unhandled  chan<- discover.ReadPacket
+
+	// BootlistFile is the path to a newline-separated ENR file, used only
+	// by the "static" backend.
+	BootlistFile string
+}
+
+// New starts the discovery backend named by backend, listening on conn with
+// local node record ln.
+func New(backend string, conn discover.UDPConn, ln *enode.LocalNode, cfg Config) (Discoverer, error) {
+	switch backend {
+	case BackendV4:
+		return discover.ListenV4(conn, ln, discover.Config{
+			PrivateKey: cfg.PrivateKey,
+			Bootnodes:  cfg.Bootnodes,
+			Unhandled:  cfg.Unhandled,
+		})
+
+	case BackendV5:
+		return discover.ListenV5(conn, ln, discover.Config{
+			PrivateKey: cfg.PrivateKey,
+			Bootnodes:  cfg.Bootnodes,
+		})
+
+	case BackendStatic:
+		return newStatic(cfg.BootlistFile)
+
+	default:
+		return nil, fmt.Errorf("unknown discovery backend: %q", backend)
+	}
+}
@@ -0,0 +1,78 @@
+package discovery
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// TaggedNode is a node discovered by one of possibly several concurrently
+// running backends, annotated with which one found it so the crawler DB can
+// record provenance.
+type TaggedNode struct {
+	Node    *enode.Node
+	Backend string
+}
+
+// Multi fans the RandomNodes iterators of several named backends into a
+// single channel of TaggedNodes, so the crawler can run discv4, discv5, and
+// static discovery concurrently while feeding one shared queue.
+type Multi struct {
+	backends map[string]Discoverer
+
+	out  chan TaggedNode
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// NewMulti starts fanning RandomNodes from every backend in backends into a
+// shared channel, readable via Nodes.
+func NewMulti(backends map[string]Discoverer) *Multi {
+	m := &Multi{
+		backends: backends,
+		out:      make(chan TaggedNode),
+		done:     make(chan struct{}),
+	}
+
+	for name, d := range backends {
+		m.wg.Add(1)
+		go m.pump(name, d)
+	}
+
+	go func() {
+		m.wg.Wait()
+		close(m.out)
+	}()
+
+	return m
+}
+
+func (m *Multi) pump(name string, d Discoverer) {
+	defer m.wg.Done()
+
+	it := d.RandomNodes()
+	defer it.Close()
+
+	for it.Next() {
+		select {
+		case m.out <- TaggedNode{Node: it.Node(), Backend: name}:
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Nodes returns the channel of discovered, backend-tagged nodes. It is
+// closed once every backend's iterator is exhausted or Close is called.
+func (m *Multi) Nodes() <-chan TaggedNode {
+	return m.out
+}
+
+// Close stops every backend and the fan-in goroutines.
+func (m *Multi) Close() {
+	close(m.done)
+	for _, d := range m.backends {
+		d.Close()
+	}
+	m.wg.Wait()
+}
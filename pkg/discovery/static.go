@@ -0,0 +1,103 @@
+package discovery
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// staticDiscoverer is a Discoverer that never looks anything up over the
+// network: it just serves the fixed set of nodes read from a bootlist file.
+// It exists so a crawl can be pointed at a closed or firewalled network
+// where discv4/discv5 traffic doesn't reach, by supplying the node list
+// out of band.
+type staticDiscoverer struct {
+	mu    sync.Mutex
+	nodes []*enode.Node
+}
+
+func newStatic(path string) (*staticDiscoverer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("static discovery backend requires a bootlist file")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bootlist file: %w", err)
+	}
+	defer f.Close()
+
+	var nodes []*enode.Node
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		n, err := enode.Parse(enode.ValidSchemes, line)
+		if err != nil {
+			return nil, fmt.Errorf("parsing bootlist entry %q: %w", line, err)
+		}
+		nodes = append(nodes, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading bootlist file: %w", err)
+	}
+
+	return &staticDiscoverer{nodes: nodes}, nil
+}
+
+// RandomNodes returns an iterator that cycles through the bootlist once.
+func (s *staticDiscoverer) RandomNodes() enode.Iterator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodes := make([]*enode.Node, len(s.nodes))
+	copy(nodes, s.nodes)
+
+	return &sliceIterator{nodes: nodes, pos: -1}
+}
+
+// Close is a no-op; the static backend holds no resources.
+func (s *staticDiscoverer) Close() {}
+
+// sliceIterator is a minimal enode.Iterator over a fixed slice of nodes.
+type sliceIterator struct {
+	mu     sync.Mutex
+	nodes  []*enode.Node
+	pos    int
+	closed bool
+}
+
+func (it *sliceIterator) Next() bool {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if it.closed || it.pos+1 >= len(it.nodes) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceIterator) Node() *enode.Node {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if it.pos < 0 || it.pos >= len(it.nodes) {
+		return nil
+	}
+	return it.nodes[it.pos]
+}
+
+func (it *sliceIterator) Close() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	it.closed = true
+}
@@ -0,0 +1,40 @@
+// Package geoip looks up the country and city for an IP address using a
+// local MaxMind GeoLite2 City database, so the crawler can annotate
+// discovered nodes with roughly where they're hosted.
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// DB wraps a MaxMind GeoLite2 City database file opened once at startup.
+type DB struct {
+	reader *geoip2.Reader
+}
+
+// Open opens the GeoLite2 City database at path.
+func Open(path string) (*DB, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening geoip database: %w", err)
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Close releases the underlying database file.
+func (d *DB) Close() error {
+	return d.reader.Close()
+}
+
+// Lookup returns the English country and city names for ip, both empty if
+// the database has no record for it.
+func (d *DB) Lookup(ip net.IP) (country, city string, err error) {
+	rec, err := d.reader.City(ip)
+	if err != nil {
+		return "", "", fmt.Errorf("looking up %s: %w", ip, err)
+	}
+	return rec.Country.Names["en"], rec.City.Names["en"], nil
+}
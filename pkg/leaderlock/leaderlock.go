@@ -0,0 +1,222 @@
+// Package leaderlock implements a named advisory lock stored in a SQLite
+// table, so that multiple `api` processes can point at the same DB files in
+// a hot-standby configuration without corrupting state. Exactly one holder
+// is allowed to run the write daemons at a time; every other instance keeps
+// serving read-only API traffic and takes over once the current leader's
+// lease expires.
+package leaderlock
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// DefaultLease is how long a held lock remains valid without a
+	// heartbeat before another instance may take over.
+	DefaultLease = 60 * time.Second
+	// DefaultHeartbeat is how often the current leader renews its lease.
+	DefaultHeartbeat = 20 * time.Second
+	// DefaultContentionBackoff is how long a non-leader waits before
+	// retrying after losing a contested acquire attempt.
+	DefaultContentionBackoff = time.Hour
+)
+
+// Lock is a named advisory lock backed by a `leader_lock` row in db.
+type Lock struct {
+	db   *sql.DB
+	name string
+	id   string
+
+	Lease             time.Duration
+	Heartbeat         time.Duration
+	ContentionBackoff time.Duration
+
+	leading atomic.Bool
+}
+
+// New returns a Lock named name, backed by db, with the package default
+// timings. The caller should override the Lease/Heartbeat/ContentionBackoff
+// fields before calling Run if different timings are needed.
+func New(db *sql.DB, name string) *Lock {
+	return &Lock{
+		db:   db,
+		name: name,
+		id:   newHolderID(),
+
+		Lease:             DefaultLease,
+		Heartbeat:         DefaultHeartbeat,
+		ContentionBackoff: DefaultContentionBackoff,
+	}
+}
+
+// CreateTable creates the leader_lock table if it does not already exist.
+// It is safe to call on every startup.
+func CreateTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS leader_lock (
+			name         TEXT PRIMARY KEY,
+			holder       TEXT NOT NULL,
+			lease_expiry DATETIME NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("creating leader_lock table: %w", err)
+	}
+
+	return nil
+}
+
+// Run blocks until ctx is cancelled. While ctx is live it repeatedly tries
+// to acquire the lease, calling becomeLeader once acquired and loseLeadership
+// when the lease is given up (either voluntarily on ctx cancellation, or
+// because a heartbeat failed to renew in time). Run only returns once the
+// lease, if held, has been released.
+func (l *Lock) Run(ctx context.Context, becomeLeader, loseLeadership func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		acquired, err := l.tryAcquire()
+		if err != nil {
+			log.Error("Failed to try acquiring leader lock", "name", l.name, "err", err)
+			sleepOrDone(ctx, l.ContentionBackoff)
+			continue
+		}
+
+		if !acquired {
+			sleepOrDone(ctx, l.ContentionBackoff)
+			continue
+		}
+
+		log.Info("Acquired leader lock", "name", l.name, "holder", l.id)
+		l.leading.Store(true)
+		becomeLeader()
+		l.holdLease(ctx)
+		l.leading.Store(false)
+		loseLeadership()
+		log.Info("Released leader lock", "name", l.name, "holder", l.id)
+	}
+}
+
+// IsLeader reports whether this instance currently holds the lease.
+func (l *Lock) IsLeader() bool {
+	return l.leading.Load()
+}
+
+// holdLease heartbeats the lease on an interval until ctx is done or
+// heartbeats fail to renew it: either the lease was lost to contention, or
+// DB errors kept it from being renewed for a full lease period.
+func (l *Lock) holdLease(ctx context.Context) {
+	ticker := time.NewTicker(l.Heartbeat)
+	defer ticker.Stop()
+
+	lastRenewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			l.release()
+			return
+		case <-ticker.C:
+			held, err := l.heartbeat()
+			if err != nil {
+				log.Error("Failed to heartbeat leader lock", "name", l.name, "err", err)
+				if time.Since(lastRenewed) >= l.Lease {
+					log.Warn("Leader lock lease ran out without renewal", "name", l.name)
+					return
+				}
+				continue
+			}
+			if !held {
+				log.Warn("Lost leader lock to contention", "name", l.name)
+				return
+			}
+			lastRenewed = time.Now()
+		}
+	}
+}
+
+func (l *Lock) tryAcquire() (bool, error) {
+	now := time.Now().UTC()
+	expiry := now.Add(l.Lease)
+
+	res, err := l.db.Exec(`
+		INSERT INTO leader_lock (name, holder, lease_expiry)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			holder = excluded.holder,
+			lease_expiry = excluded.lease_expiry
+		WHERE leader_lock.lease_expiry < ?
+	`, l.name, l.id, expiry, now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (l *Lock) heartbeat() (bool, error) {
+	expiry := time.Now().UTC().Add(l.Lease)
+
+	res, err := l.db.Exec(`
+		UPDATE leader_lock
+		SET lease_expiry = ?
+		WHERE name = ? AND holder = ?
+	`, expiry, l.name, l.id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (l *Lock) release() {
+	_, err := l.db.Exec(`
+		DELETE FROM leader_lock WHERE name = ? AND holder = ?
+	`, l.name, l.id)
+	if err != nil {
+		log.Error("Failed to release leader lock", "name", l.name, "err", err)
+	}
+}
+
+func sleepOrDone(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func newHolderID() string {
+	var buf [8]byte
+	_, _ = rand.Read(buf[:])
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d-%x", host, os.Getpid(), buf)
+}
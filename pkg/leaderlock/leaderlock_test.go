@@ -0,0 +1,175 @@
+package leaderlock
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "leaderlock.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := CreateTable(db); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	return db
+}
+
+func TestTryAcquireContention(t *testing.T) {
+	db := openTestDB(t)
+
+	a := New(db, "leader")
+	a.Lease = time.Hour
+
+	acquired, err := a.tryAcquire()
+	if err != nil {
+		t.Fatalf("tryAcquire: %v", err)
+	}
+	if !acquired {
+		t.Fatal("first tryAcquire should succeed on an empty table")
+	}
+
+	b := New(db, "leader")
+	b.Lease = time.Hour
+
+	acquired, err = b.tryAcquire()
+	if err != nil {
+		t.Fatalf("tryAcquire: %v", err)
+	}
+	if acquired {
+		t.Fatal("second tryAcquire should fail while the first holder's lease is live")
+	}
+}
+
+func TestTryAcquireSucceedsAfterLeaseExpires(t *testing.T) {
+	db := openTestDB(t)
+
+	a := New(db, "leader")
+	a.Lease = -time.Second // already expired as soon as it's written
+
+	acquired, err := a.tryAcquire()
+	if err != nil || !acquired {
+		t.Fatalf("tryAcquire = %v, %v, want true, nil", acquired, err)
+	}
+
+	b := New(db, "leader")
+	b.Lease = time.Hour
+
+	acquired, err = b.tryAcquire()
+	if err != nil {
+		t.Fatalf("tryAcquire: %v", err)
+	}
+	if !acquired {
+		t.Fatal("tryAcquire should succeed once the previous holder's lease has expired")
+	}
+}
+
+func TestHeartbeatOnlyExtendsOwnLease(t *testing.T) {
+	db := openTestDB(t)
+
+	a := New(db, "leader")
+	a.Lease = time.Hour
+	if acquired, err := a.tryAcquire(); err != nil || !acquired {
+		t.Fatalf("tryAcquire = %v, %v, want true, nil", acquired, err)
+	}
+
+	held, err := a.heartbeat()
+	if err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if !held {
+		t.Fatal("heartbeat should report held=true for the current holder")
+	}
+
+	b := New(db, "leader")
+	held, err = b.heartbeat()
+	if err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if held {
+		t.Fatal("heartbeat should report held=false for a holder that never acquired the lock")
+	}
+}
+
+func TestReleaseAllowsImmediateReacquisition(t *testing.T) {
+	db := openTestDB(t)
+
+	a := New(db, "leader")
+	a.Lease = time.Hour
+	if acquired, err := a.tryAcquire(); err != nil || !acquired {
+		t.Fatalf("tryAcquire = %v, %v, want true, nil", acquired, err)
+	}
+
+	a.release()
+
+	b := New(db, "leader")
+	b.Lease = time.Hour
+	acquired, err := b.tryAcquire()
+	if err != nil {
+		t.Fatalf("tryAcquire: %v", err)
+	}
+	if !acquired {
+		t.Fatal("tryAcquire should succeed immediately after the previous holder released")
+	}
+}
+
+func TestRunReportsIsLeaderAndReleasesOnCancel(t *testing.T) {
+	db := openTestDB(t)
+
+	l := New(db, "leader")
+	l.Lease = time.Hour
+	l.Heartbeat = time.Hour
+	l.ContentionBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	became := make(chan struct{})
+	lost := make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx, func() { close(became) }, func() { close(lost) })
+		close(done)
+	}()
+
+	select {
+	case <-became:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run never called becomeLeader")
+	}
+
+	if !l.IsLeader() {
+		t.Fatal("IsLeader() should be true once becomeLeader has fired")
+	}
+
+	cancel()
+
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run never called loseLeadership after cancellation")
+	}
+	<-done
+
+	if l.IsLeader() {
+		t.Fatal("IsLeader() should be false once Run has returned")
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM leader_lock WHERE name = ?`, "leader").Scan(&count); err != nil {
+		t.Fatalf("querying leader_lock: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("leader_lock row count = %d, want 0 after release", count)
+	}
+}
@@ -0,0 +1,64 @@
+// Package readiness tracks whether the daemons launched by `api` have
+// completed enough work to be considered ready, for use by a /readyz
+// handler. It follows the same non-blocking select-on-a-closed-channel
+// pattern LXD's waitready uses: a signal is a closed channel, checking
+// readiness is a non-blocking select, and there is no way to "unready"
+// once every expected signal has arrived.
+package readiness
+
+import "sync"
+
+// Gate becomes ready once every one of a fixed number of signals has been
+// reported exactly once.
+type Gate struct {
+	mu      sync.Mutex
+	pending int
+	ch      chan struct{}
+}
+
+// NewGate returns a Gate that becomes ready after Signal has been called n
+// times (once per distinct precondition being tracked).
+func NewGate(n int) *Gate {
+	g := &Gate{
+		pending: n,
+		ch:      make(chan struct{}),
+	}
+	if n <= 0 {
+		close(g.ch)
+	}
+
+	return g
+}
+
+// Signal reports that one of the preconditions has been met. Once all of
+// them have, the gate becomes ready and stays that way.
+func (g *Gate) Signal() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.pending <= 0 {
+		return
+	}
+
+	g.pending--
+	if g.pending == 0 {
+		close(g.ch)
+	}
+}
+
+// Ready reports whether every precondition has been signalled, without
+// blocking.
+func (g *Gate) Ready() bool {
+	select {
+	case <-g.ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done returns the channel that closes once the gate becomes ready, for
+// callers that want to select on it directly.
+func (g *Gate) Done() <-chan struct{} {
+	return g.ch
+}
@@ -0,0 +1,81 @@
+package readiness
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestGateReadyAfterAllSignals(t *testing.T) {
+	g := NewGate(2)
+
+	if g.Ready() {
+		t.Fatal("gate should not be ready before any signal")
+	}
+
+	g.Signal()
+	if g.Ready() {
+		t.Fatal("gate should not be ready after only one of two signals")
+	}
+
+	g.Signal()
+	if !g.Ready() {
+		t.Fatal("gate should be ready once every signal has arrived")
+	}
+}
+
+func TestGateExtraSignalsAreNoops(t *testing.T) {
+	g := NewGate(1)
+
+	g.Signal()
+	g.Signal()
+	g.Signal()
+
+	if !g.Ready() {
+		t.Fatal("gate should be ready")
+	}
+}
+
+func TestNewGateZeroIsImmediatelyReady(t *testing.T) {
+	g := NewGate(0)
+
+	if !g.Ready() {
+		t.Fatal("NewGate(0) should be immediately ready")
+	}
+}
+
+func TestGateDoneClosesOnceReady(t *testing.T) {
+	g := NewGate(1)
+
+	select {
+	case <-g.Done():
+		t.Fatal("Done channel should not be closed yet")
+	default:
+	}
+
+	g.Signal()
+
+	select {
+	case <-g.Done():
+	default:
+		t.Fatal("Done channel should be closed once the gate is ready")
+	}
+}
+
+func TestGateConcurrentSignals(t *testing.T) {
+	const n = 100
+	g := NewGate(n)
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			g.Signal()
+		}()
+	}
+	wg.Wait()
+
+	if !g.Ready() {
+		t.Fatal("gate should be ready after every goroutine signals once")
+	}
+}
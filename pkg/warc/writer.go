@@ -0,0 +1,224 @@
+// Package warc writes crawl results as WARC (Web ARChive) records to a
+// rotating, gzip-compressed file, so that operators have a durable,
+// tool-agnostic archive of crawl results alongside the SQLite DBs.
+//
+// The format follows ISO 28500 closely enough for the `crawl`/`warcio`
+// family of tools to read it back: a single `warcinfo` record at the start
+// of each file followed by one `metadata` record per crawled node.
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// NodeRecord is the set of fields captured for each successfully
+// handshaked node.
+type NodeRecord struct {
+	EnodeURL     string
+	ClientName   string
+	ClientVer    string
+	Capabilities []string
+	ForkID       string
+	Timestamp    time.Time
+	GeoIPCountry string
+	GeoIPCity    string
+}
+
+// Writer appends NodeRecords to a rotating sequence of gzip-compressed WARC
+// files rooted at dir. Writer is safe for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+
+	dir         string
+	prefix      string
+	maxFileSize int64
+
+	file      *os.File
+	gz        *gzip.Writer
+	written   int64
+	fileIndex int
+}
+
+// NewWriter creates a Writer that rotates to a new file once the
+// uncompressed bytes written to the current one reach maxFileSize; a
+// maxFileSize of zero or less disables rotation. Files are named
+// "<prefix>-<index>-<timestamp>.warc.gz" inside dir.
+func NewWriter(dir, prefix string, maxFileSize int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating warc output dir: %w", err)
+	}
+
+	w := &Writer{
+		dir:         dir,
+		prefix:      prefix,
+		maxFileSize: maxFileSize,
+	}
+
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// WriteNode appends rec to the archive as a `metadata` WARC record,
+// rotating the underlying file first if it has reached maxFileSize.
+func (w *Writer) WriteNode(rec NodeRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxFileSize > 0 && w.written >= w.maxFileSize {
+		if err := w.rotate(); err != nil {
+			return fmt.Errorf("rotating warc file: %w", err)
+		}
+	}
+
+	body, err := formatMetadataBlock(rec)
+	if err != nil {
+		return fmt.Errorf("marshaling node record: %w", err)
+	}
+
+	return w.writeRecord(warcRecord{
+		recordType:  "metadata",
+		targetURI:   rec.EnodeURL,
+		date:        rec.Timestamp,
+		contentType: "application/json",
+		body:        body,
+	})
+}
+
+// Close flushes and closes the currently open file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.closeCurrent()
+}
+
+func (w *Writer) closeCurrent() error {
+	if w.gz == nil {
+		return nil
+	}
+
+	if err := w.gz.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
+
+func (w *Writer) rotate() error {
+	if err := w.closeCurrent(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%05d-%s.warc.gz", w.prefix, w.fileIndex, time.Now().UTC().Format("20060102150405"))
+	path := filepath.Join(w.dir, name)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	w.file = f
+	w.gz = gzip.NewWriter(f)
+	w.written = 0
+	w.fileIndex++
+
+	return w.writeRecord(warcinfoRecord())
+}
+
+type warcRecord struct {
+	recordType  string
+	targetURI   string
+	date        time.Time
+	contentType string
+	body        []byte
+}
+
+func warcinfoRecord() warcRecord {
+	body := []byte("software: node-crawler\r\nformat: WARC File Format 1.1\r\n")
+
+	return warcRecord{
+		recordType:  "warcinfo",
+		date:        time.Now().UTC(),
+		contentType: "application/warc-fields",
+		body:        body,
+	}
+}
+
+func (w *Writer) writeRecord(r warcRecord) error {
+	id := newRecordID()
+
+	header := fmt.Sprintf(
+		"WARC/1.1\r\n"+
+			"WARC-Type: %s\r\n"+
+			"WARC-Record-ID: %s\r\n"+
+			"WARC-Date: %s\r\n"+
+			"Content-Length: %d\r\n"+
+			"Content-Type: %s\r\n",
+		r.recordType,
+		id,
+		r.date.Format(time.RFC3339),
+		len(r.body),
+		r.contentType,
+	)
+	if r.targetURI != "" {
+		header += fmt.Sprintf("WARC-Target-URI: %s\r\n", r.targetURI)
+	}
+	header += "\r\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.Write(r.body)
+	buf.WriteString("\r\n\r\n")
+
+	n, err := buf.WriteTo(w.gz)
+	if err != nil {
+		return err
+	}
+	w.written += n
+
+	return w.gz.Flush()
+}
+
+func newRecordID() string {
+	var buf [16]byte
+	_, _ = rand.Read(buf[:])
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16])
+}
+
+// metadataBlock is the JSON shape written as the body of each `metadata`
+// WARC record.
+type metadataBlock struct {
+	Enode         string   `json:"enode"`
+	ClientName    string   `json:"clientName"`
+	ClientVersion string   `json:"clientVersion"`
+	Capabilities  []string `json:"capabilities"`
+	ForkID        string   `json:"forkID"`
+	Timestamp     string   `json:"timestamp"`
+	GeoIPCountry  string   `json:"geoipCountry"`
+	GeoIPCity     string   `json:"geoipCity"`
+}
+
+func formatMetadataBlock(rec NodeRecord) ([]byte, error) {
+	return json.Marshal(metadataBlock{
+		Enode:         rec.EnodeURL,
+		ClientName:    rec.ClientName,
+		ClientVersion: rec.ClientVer,
+		Capabilities:  rec.Capabilities,
+		ForkID:        rec.ForkID,
+		Timestamp:     rec.Timestamp.Format(time.RFC3339),
+		GeoIPCountry:  rec.GeoIPCountry,
+		GeoIPCity:     rec.GeoIPCity,
+	})
+}
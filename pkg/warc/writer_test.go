@@ -0,0 +1,178 @@
+package warc
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readGzipFiles(t *testing.T, dir string) []byte {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading dir: %v", err)
+	}
+
+	var all []byte
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatalf("opening %s: %v", e.Name(), err)
+		}
+		defer f.Close()
+
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("opening gzip reader for %s: %v", e.Name(), err)
+		}
+		defer gz.Close()
+
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("reading %s: %v", e.Name(), err)
+		}
+		all = append(all, data...)
+	}
+
+	return all
+}
+
+func TestWriteNodeFormatsWarcRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewWriter(dir, "test", 0)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	defer w.Close()
+
+	rec := NodeRecord{
+		EnodeURL:     "enode://aabb@1.2.3.4:30303",
+		ClientName:   "Geth",
+		ClientVer:    "v1.13.5",
+		Capabilities: []string{"eth/68"},
+		ForkID:       "deadbeef",
+		Timestamp:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		GeoIPCountry: "United States",
+		GeoIPCity:    "Ashburn",
+	}
+	if err := w.WriteNode(rec); err != nil {
+		t.Fatalf("WriteNode: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	content := string(readGzipFiles(t, dir))
+
+	if !strings.Contains(content, "WARC-Type: warcinfo") {
+		t.Error("missing warcinfo record")
+	}
+	if !strings.Contains(content, "WARC-Type: metadata") {
+		t.Error("missing metadata record")
+	}
+	if !strings.Contains(content, "WARC-Target-URI: "+rec.EnodeURL) {
+		t.Error("missing WARC-Target-URI for the node")
+	}
+
+	idx := strings.Index(content, "\r\n\r\n{")
+	if idx == -1 {
+		t.Fatal("could not find JSON metadata body")
+	}
+	bodyEnd := strings.Index(content[idx+4:], "\r\n\r\n")
+	if bodyEnd == -1 {
+		t.Fatal("could not find end of JSON metadata body")
+	}
+	body := content[idx+4 : idx+4+bodyEnd]
+
+	var got metadataBlock
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("unmarshaling metadata body: %v", err)
+	}
+	if got.Enode != rec.EnodeURL {
+		t.Errorf("Enode = %q, want %q", got.Enode, rec.EnodeURL)
+	}
+	if got.ForkID != rec.ForkID {
+		t.Errorf("ForkID = %q, want %q", got.ForkID, rec.ForkID)
+	}
+	if got.ClientName != rec.ClientName {
+		t.Errorf("ClientName = %q, want %q", got.ClientName, rec.ClientName)
+	}
+}
+
+func TestWriteNodeRotatesOnceMaxFileSizeExceeded(t *testing.T) {
+	rec := NodeRecord{EnodeURL: "enode://aabb@1.2.3.4:30303", Timestamp: time.Now()}
+
+	// Figure out how many (uncompressed) bytes one warcinfo + metadata
+	// record takes, so maxFileSize can be set to fit exactly one record
+	// per file: the first WriteNode should fit without rotating and bring
+	// written up to the limit, so the second should rotate before writing.
+	probe, err := NewWriter(t.TempDir(), "test", 0)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := probe.WriteNode(rec); err != nil {
+		t.Fatalf("WriteNode: %v", err)
+	}
+	maxFileSize := probe.written
+	probe.Close()
+
+	dir := t.TempDir()
+	w, err := NewWriter(dir, "test", maxFileSize)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.WriteNode(rec); err != nil {
+		t.Fatalf("WriteNode: %v", err)
+	}
+	if err := w.WriteNode(rec); err != nil {
+		t.Fatalf("WriteNode: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d files, want 2 (one rotation before the second WriteNode, once maxFileSize was reached)", len(entries))
+	}
+}
+
+func TestWriteNodeNoRotationWhenUnbounded(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewWriter(dir, "test", 0)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	defer w.Close()
+
+	rec := NodeRecord{EnodeURL: "enode://aabb@1.2.3.4:30303", Timestamp: time.Now()}
+	for i := 0; i < 5; i++ {
+		if err := w.WriteNode(rec); err != nil {
+			t.Fatalf("WriteNode: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d files, want 1 (maxFileSize <= 0 disables rotation)", len(entries))
+	}
+}